@@ -1,61 +1,102 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+
+	"github.com/OlegSchwann/AdTechHackaton2023/storage"
+	"github.com/OlegSchwann/AdTechHackaton2023/web"
 )
 
 type Application struct {
-	storage *Storage
+	storage *storage.Storage
 }
 
 func (a *Application) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	db, _ := a.storage.db.DB()
-	err := db.Ping()
+	db, err := a.storage.DB()
+	if err == nil {
+		err = db.Ping()
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		web.ServiceUnavailable(w, r, err)
 		return
 	}
-	w.Write([]byte("ok"))
+	web.OK(w, r, map[string]string{"status": "ok"})
 }
 
+// defaultCategoryDepth bounds how many levels GetCategories walks when
+// nested=1 is requested without an explicit depth.
+const defaultCategoryDepth = 3
+
 func (a *Application) GetCategories(w http.ResponseWriter, r *http.Request) {
-	parentId, _ := strconv.Atoi(r.URL.Query().Get("parent"))
+	query := r.URL.Query()
+	parentId, _ := strconv.Atoi(query.Get("parent"))
+
+	if nested, _ := strconv.ParseBool(query.Get("nested")); nested {
+		depth, err := strconv.Atoi(query.Get("depth"))
+		if err != nil || depth <= 0 {
+			depth = defaultCategoryDepth
+		}
+
+		categories, err := a.storage.GetCategoriesNested(parentId, depth)
+		if err != nil {
+			web.InternalServerError(w, r, err)
+			return
+		}
+
+		web.OK(w, r, categories)
+		return
+	}
 
 	categories, err := a.storage.GetCategories(parentId)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		web.InternalServerError(w, r, err)
 		return
 	}
 
-	enc := json.NewEncoder(w)
-	enc.SetEscapeHTML(false)
-	enc.Encode(categories)
+	web.OK(w, r, categories)
 }
 
-func (a *Application) GetPartners(w http.ResponseWriter, _ *http.Request) {
+func (a *Application) GetPartners(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("format") == "geojson" {
+		geojson, err := a.storage.GetPartnersGeoJSON()
+		if err != nil {
+			web.InternalServerError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.Write(geojson)
+		return
+	}
+
 	partners, err := a.storage.GetPartners()
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		web.InternalServerError(w, r, err)
 		return
 	}
 
-	enc := json.NewEncoder(w)
-	enc.SetEscapeHTML(false)
-	enc.Encode(partners)
+	web.OK(w, r, partners)
 }
 
 func (a *Application) GetImage(w http.ResponseWriter, r *http.Request) {
 	url := r.URL.Query().Get("url")
+	if url == "" {
+		web.BadRequest(w, r, "url is required")
+		return
+	}
 
 	image, err := a.storage.GetBannerImageByURL(url)
+	if errors.Is(err, sql.ErrNoRows) {
+		web.NotFound(w, r)
+		return
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		web.InternalServerError(w, r, err)
 		return
 	}
 
@@ -63,26 +104,117 @@ func (a *Application) GetImage(w http.ResponseWriter, r *http.Request) {
 	w.Write(image)
 }
 
+// defaultPromotionsLimit bounds how many promotions the lat/long search of
+// GetPromotions returns when the caller doesn't pass an explicit limit.
+// Promotions listed by partner are not paged: limit and offset are ignored there.
+const defaultPromotionsLimit = 50
+
 func (a *Application) GetPromotions(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	partner, _ := strconv.Atoi(query.Get("partner"))
 	latitude, _ := strconv.ParseFloat(query.Get("lat"), 64)
 	longitude, _ := strconv.ParseFloat(query.Get("long"), 64)
+	radius, _ := strconv.ParseFloat(query.Get("radius"), 64)
+	geo := latitude != 0 && longitude != 0
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPromotionsLimit
+	}
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	if query.Get("format") == "geojson" {
+		var geojson []byte
+		if geo {
+			geojson, err = a.storage.GetPromotionsByGeoGeoJSON(latitude, longitude, radius, limit, offset)
+		} else {
+			geojson, err = a.storage.GetPromotionsByPartnerGeoJSON(partner)
+		}
+		if err != nil {
+			web.InternalServerError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.Write(geojson)
+		return
+	}
 
-	var promotions []Promotion
-	var err error
-	if latitude != 0 && longitude != 0 {
-		promotions, err = a.storage.GetPromotionsByGeo(latitude, longitude)
+	var promotions []storage.Promotion
+	if geo {
+		promotions, err = a.storage.GetPromotionsByGeo(latitude, longitude, radius, limit, offset)
 	} else {
 		promotions, err = a.storage.GetPromotionsByPartner(partner)
 	}
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		web.InternalServerError(w, r, err)
+		return
+	}
+
+	web.OK(w, r, promotions)
+}
+
+// actionTypes are the values allowed by the action.type check constraint.
+var actionTypes = map[string]bool{"taken": true, "expended": true}
+
+func (a *Application) RecordAction(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		UserId      int    `json:"user_id"`
+		PromotionId int    `json:"promotion_id"`
+		Type        string `json:"type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		web.BadRequest(w, r, "malformed request body")
+		return
+	}
+	if !actionTypes[body.Type] {
+		web.BadRequest(w, r, `type must be "taken" or "expended"`)
+		return
+	}
+
+	action, err := a.storage.RecordAction(body.UserId, body.PromotionId, body.Type)
+	if err != nil {
+		web.InternalServerError(w, r, err)
+		return
+	}
+
+	web.OK(w, r, action)
+}
+
+// GetUserPromotions handles GET /users/{id}/promotions?state=taken|expended,
+// returning the promotions a user has taken or expended for their coupon wallet.
+func (a *Application) GetUserPromotions(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/users/"), "/promotions")
+	userId, err := strconv.Atoi(rest)
+	if err != nil {
+		web.BadRequest(w, r, "invalid user id")
+		return
+	}
+
+	promotions, err := a.storage.GetUserPromotions(userId, r.URL.Query().Get("state"))
+	if err != nil {
+		web.InternalServerError(w, r, err)
+		return
+	}
+
+	web.OK(w, r, promotions)
+}
+
+// GetPartnerStats handles GET /partners/{id}/stats, returning aggregated
+// taken/expended counts per promotion for partner-facing dashboards.
+func (a *Application) GetPartnerStats(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/partners/"), "/stats")
+	partnerId, err := strconv.Atoi(rest)
+	if err != nil {
+		web.BadRequest(w, r, "invalid partner id")
+		return
+	}
+
+	stats, err := a.storage.GetPartnerStats(partnerId)
+	if err != nil {
+		web.InternalServerError(w, r, err)
 		return
 	}
 
-	enc := json.NewEncoder(w)
-	enc.SetEscapeHTML(false)
-	enc.Encode(promotions)
+	web.OK(w, r, stats)
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+var geoCacheBucket = []byte("geocode")
+
+type coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// geoCache memoizes address -> coordinates lookups against an external
+// geocoder, so the same partner address is never billed or rate-limited
+// twice across ingest runs.
+type geoCache struct {
+	db *bbolt.DB
+}
+
+func openGeoCache(path string) (*geoCache, error) {
+	db, err := bbolt.Open(path, 0o644, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("openGeoCache: %w", err)
+	}
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(geoCacheBucket)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("openGeoCache: %w", err)
+	}
+	return &geoCache{db: db}, nil
+}
+
+func (c *geoCache) Close() error {
+	return c.db.Close()
+}
+
+// Geocode resolves address to coordinates, serving the cached result after
+// the first lookup.
+func (c *geoCache) Geocode(address string) (coordinates, error) {
+	key := []byte(address)
+
+	var cached coordinates
+	var found bool
+	if err := c.db.View(func(tx *bbolt.Tx) error {
+		if raw := tx.Bucket(geoCacheBucket).Get(key); raw != nil {
+			found = true
+			return json.Unmarshal(raw, &cached)
+		}
+		return nil
+	}); err != nil {
+		return coordinates{}, fmt.Errorf("geoCache.Geocode(%q): %w", address, err)
+	}
+	if found {
+		return cached, nil
+	}
+
+	resolved, err := geocode(address)
+	if err != nil {
+		return coordinates{}, fmt.Errorf("geoCache.Geocode(%q): %w", address, err)
+	}
+
+	raw, err := json.Marshal(resolved)
+	if err != nil {
+		return coordinates{}, fmt.Errorf("geoCache.Geocode(%q): %w", address, err)
+	}
+	if err := c.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(geoCacheBucket).Put(key, raw)
+	}); err != nil {
+		return coordinates{}, fmt.Errorf("geoCache.Geocode(%q): %w", address, err)
+	}
+
+	return resolved, nil
+}
+
+// geocode resolves a free-form address via the Nominatim/OpenStreetMap
+// public geocoder. Swap this out for a commercial provider if rate limits
+// become a problem.
+func geocode(address string) (coordinates, error) {
+	endpoint := "https://nominatim.openstreetmap.org/search?format=json&limit=1&q=" + url.QueryEscape(address)
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return coordinates{}, fmt.Errorf("geocode(%q): %w", address, err)
+	}
+	req.Header.Set("User-Agent", "AdTechHackaton2023-ingest")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return coordinates{}, fmt.Errorf("geocode(%q): %w", address, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return coordinates{}, fmt.Errorf("geocode(%q): %w", address, err)
+	}
+
+	var results []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.Unmarshal(body, &results); err != nil {
+		return coordinates{}, fmt.Errorf("geocode(%q): %w", address, err)
+	}
+	if len(results) == 0 {
+		return coordinates{}, fmt.Errorf("geocode(%q): no match", address)
+	}
+
+	var lat, lon float64
+	if _, err := fmt.Sscanf(results[0].Lat, "%g", &lat); err != nil {
+		return coordinates{}, fmt.Errorf("geocode(%q): parsing latitude: %w", address, err)
+	}
+	if _, err := fmt.Sscanf(results[0].Lon, "%g", &lon); err != nil {
+		return coordinates{}, fmt.Errorf("geocode(%q): parsing longitude: %w", address, err)
+	}
+
+	return coordinates{Latitude: lat, Longitude: lon}, nil
+}
@@ -0,0 +1,196 @@
+// Command ingest seeds the partner, promotion and headline_banner tables from
+// a list of public review-site URLs. It writes no category rows: categories
+// still come from the hardcoded block in storage's initSQL, and every scraped
+// promotion is stored under category 0 (Root).
+//
+//	go run ./ingest -urls urls.txt -webcache web.db -geocache geo.db
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/OlegSchwann/AdTechHackaton2023/storage"
+)
+
+// scrapedPartner is a partner as extracted from a page, plus the street
+// address used to geocode it. Address is kept separate from
+// storage.Partner.Description so the raw address scraped off the page is
+// never served back to clients as the partner's description.
+type scrapedPartner struct {
+	storage.Partner
+	Address string
+}
+
+// Scraper extracts a partner and its promotions from a single downloaded
+// page, plus the URL of its headline image, if any.
+type Scraper interface {
+	Scrape(doc *goquery.Document) ([]scrapedPartner, []storage.Promotion, error)
+	BannerURL(doc *goquery.Document) string
+}
+
+// canonicalLink returns the page's canonical URL, which tabelogScraper and
+// tripAdvisorScraper both parse to derive a partner's numeric id.
+func canonicalLink(doc *goquery.Document) (string, error) {
+	href, ok := doc.Find("link[rel=canonical]").Attr("href")
+	if !ok {
+		return "", fmt.Errorf("canonicalLink: page has no canonical link")
+	}
+	return href, nil
+}
+
+// scraperFor picks the site-specific Scraper for a page based on its host.
+func scraperFor(host string) Scraper {
+	host = strings.TrimPrefix(host, "www.")
+	switch {
+	case strings.HasSuffix(host, "tabelog.com"):
+		return tabelogScraper{}
+	case strings.HasSuffix(host, "tripadvisor.com"):
+		return tripAdvisorScraper{}
+	default:
+		return nil
+	}
+}
+
+func main() {
+	urlsPath := flag.String("urls", "", "path to a file of newline-separated review-site URLs to scrape")
+	webCachePath := flag.String("webcache", "web.db", "path to the bbolt database caching downloaded pages")
+	geoCachePath := flag.String("geocache", "geo.db", "path to the bbolt database caching geocoder results")
+	flag.Parse()
+
+	if *urlsPath == "" {
+		log.Fatal("ingest: -urls is required")
+	}
+
+	urls, err := readURLs(*urlsPath)
+	if err != nil {
+		log.Fatalf("ingest: %s", err.Error())
+	}
+
+	pages, err := openWebCache(*webCachePath)
+	if err != nil {
+		log.Fatalf("ingest: %s", err.Error())
+	}
+	defer pages.Close()
+
+	geo, err := openGeoCache(*geoCachePath)
+	if err != nil {
+		log.Fatalf("ingest: %s", err.Error())
+	}
+	defer geo.Close()
+
+	db, err := gorm.Open(postgres.Open(os.Getenv("POSTGRESQL")), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("ingest: gorm.Open(postgres.Open(%q)): %s", os.Getenv("POSTGRESQL"), err.Error())
+	}
+	store, err := storage.NewStorage(db)
+	if err != nil {
+		log.Fatalf("ingest: NewStorage: %s", err.Error())
+	}
+
+	for _, rawURL := range urls {
+		if err := ingestOne(rawURL, pages, geo, store); err != nil {
+			log.Printf("ingest: %s: %s", rawURL, err.Error())
+			continue
+		}
+		log.Printf("ingest: %s: done", rawURL)
+	}
+}
+
+func ingestOne(rawURL string, pages *webCache, geo *geoCache, store *storage.Storage) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("ingestOne(%q): %w", rawURL, err)
+	}
+
+	scraper := scraperFor(parsed.Host)
+	if scraper == nil {
+		return fmt.Errorf("ingestOne(%q): no scraper for host %q", rawURL, parsed.Host)
+	}
+
+	body, err := pages.Get(rawURL)
+	if err != nil {
+		return fmt.Errorf("ingestOne(%q): %w", rawURL, err)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("ingestOne(%q): %w", rawURL, err)
+	}
+
+	partners, promotions, err := scraper.Scrape(doc)
+	if err != nil {
+		return fmt.Errorf("ingestOne(%q): %w", rawURL, err)
+	}
+
+	for _, sp := range partners {
+		coords, err := geo.Geocode(sp.Address)
+		if err != nil {
+			return fmt.Errorf("ingestOne(%q): geocoding partner %d: %w", rawURL, sp.Id, err)
+		}
+		sp.Latitude, sp.Longitude = coords.Latitude, coords.Longitude
+
+		if err := store.UpsertPartner(sp.Partner); err != nil {
+			return fmt.Errorf("ingestOne(%q): %w", rawURL, err)
+		}
+
+		if bannerURL := scraper.BannerURL(doc); bannerURL != "" {
+			if err := ingestBanner(bannerURL, sp.Id, pages, store); err != nil {
+				// A missing/broken banner image shouldn't sink the partner
+				// and promotions we already scraped successfully.
+				log.Printf("ingestOne(%q): %s", rawURL, err.Error())
+			}
+		}
+	}
+
+	for _, promotion := range promotions {
+		if err := store.UpsertPromotion(promotion); err != nil {
+			return fmt.Errorf("ingestOne(%q): %w", rawURL, err)
+		}
+	}
+
+	return nil
+}
+
+func ingestBanner(bannerURL string, partnerId int, pages *webCache, store *storage.Storage) error {
+	image, err := pages.Get(bannerURL)
+	if err != nil {
+		return fmt.Errorf("ingestBanner(%q): %w", bannerURL, err)
+	}
+	if err := store.UpsertBanner(storage.Banner{URL: bannerURL, PartnerId: &partnerId, Image: image}); err != nil {
+		return fmt.Errorf("ingestBanner(%q): %w", bannerURL, err)
+	}
+	return nil
+}
+
+func readURLs(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("readURLs(%q): %w", path, err)
+	}
+	defer f.Close()
+
+	var urls []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("readURLs(%q): %w", path, err)
+	}
+
+	return urls, nil
+}
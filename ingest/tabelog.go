@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/OlegSchwann/AdTechHackaton2023/storage"
+)
+
+// tabelogScraper reads a Tabelog restaurant page: one partner per page, plus
+// any coupons listed in the page's promotion section.
+type tabelogScraper struct{}
+
+func (tabelogScraper) Scrape(doc *goquery.Document) ([]scrapedPartner, []storage.Promotion, error) {
+	id, err := tabelogID(doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tabelogScraper.Scrape: %w", err)
+	}
+
+	partner := scrapedPartner{
+		Partner: storage.Partner{
+			Id:          id,
+			Headline:    strings.TrimSpace(doc.Find(".display-name").First().Text()),
+			Description: strings.TrimSpace(doc.Find(".rdheader-subinfo__catch-copy").First().Text()),
+			PriceLevel:  tabelogPriceLevel(doc),
+		},
+		Address: strings.TrimSpace(doc.Find(".rstinfo-table__address").First().Text()),
+	}
+
+	var promotions []storage.Promotion
+	doc.Find(".rstdtl-coupon-list__item").Each(func(i int, sel *goquery.Selection) {
+		promotions = append(promotions, storage.Promotion{
+			Id:          id*1000 + i,
+			PartnerId:   id,
+			Title:       strings.TrimSpace(sel.Find(".rstdtl-coupon-list__coupon-title").Text()),
+			Description: strings.TrimSpace(sel.Find(".rstdtl-coupon-list__coupon-explanation").Text()),
+		})
+	})
+
+	return []scrapedPartner{partner}, promotions, nil
+}
+
+func (tabelogScraper) BannerURL(doc *goquery.Document) string {
+	src, _ := doc.Find(".rstdtl-top-photo img").First().Attr("src")
+	return src
+}
+
+// tabelogID derives a stable numeric id from the page's rest ID, which
+// Tabelog embeds in its canonical link (.../A1234/A123456/12345678/).
+func tabelogID(doc *goquery.Document) (int, error) {
+	href, err := canonicalLink(doc)
+	if err != nil {
+		return 0, fmt.Errorf("tabelogID: %w", err)
+	}
+
+	parts := strings.Split(strings.Trim(href, "/"), "/")
+	id, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return 0, fmt.Errorf("tabelogID: parsing id out of canonical link %q: %w", href, err)
+	}
+	return id, nil
+}
+
+func tabelogPriceLevel(doc *goquery.Document) int8 {
+	return int8(doc.Find(".rstinfo-budget .c-rating-v3__val").Length())
+}
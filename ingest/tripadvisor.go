@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/OlegSchwann/AdTechHackaton2023/storage"
+)
+
+// tripAdvisorScraper reads a TripAdvisor "Restaurant_Review" page: one
+// partner per page, plus any deals listed in the page's offers section.
+type tripAdvisorScraper struct{}
+
+func (tripAdvisorScraper) Scrape(doc *goquery.Document) ([]scrapedPartner, []storage.Promotion, error) {
+	id, err := tripAdvisorID(doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tripAdvisorScraper.Scrape: %w", err)
+	}
+
+	partner := scrapedPartner{
+		Partner: storage.Partner{
+			Id:          id,
+			Headline:    strings.TrimSpace(doc.Find("h1").First().Text()),
+			Description: strings.TrimSpace(doc.Find("[data-test-target=restaurant-detail-overview]").First().Text()),
+			PriceLevel:  tripAdvisorPriceLevel(doc),
+		},
+		Address: strings.TrimSpace(doc.Find("[data-test-target=restaurant-detail-info] address").First().Text()),
+	}
+
+	var promotions []storage.Promotion
+	doc.Find("[data-test-target=offer-card]").Each(func(i int, sel *goquery.Selection) {
+		promotions = append(promotions, storage.Promotion{
+			Id:          id*1000 + i,
+			PartnerId:   id,
+			Title:       strings.TrimSpace(sel.Find(".offerTitle").Text()),
+			Description: strings.TrimSpace(sel.Find(".offerDescription").Text()),
+		})
+	})
+
+	return []scrapedPartner{partner}, promotions, nil
+}
+
+func (tripAdvisorScraper) BannerURL(doc *goquery.Document) string {
+	src, _ := doc.Find("[data-test-target=photo-viewer-photo] img").First().Attr("src")
+	return src
+}
+
+// tripAdvisorID pulls the numeric location id out of the canonical URL, e.g.
+// .../Restaurant_Review-g123-d456789-Reviews-... -> 456789.
+func tripAdvisorID(doc *goquery.Document) (int, error) {
+	href, err := canonicalLink(doc)
+	if err != nil {
+		return 0, fmt.Errorf("tripAdvisorID: %w", err)
+	}
+
+	for _, part := range strings.Split(href, "-") {
+		if strings.HasPrefix(part, "d") {
+			if id, err := strconv.Atoi(part[1:]); err == nil {
+				return id, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("tripAdvisorID: canonical link %q has no location id", href)
+}
+
+func tripAdvisorPriceLevel(doc *goquery.Document) int8 {
+	return int8(len(strings.TrimSpace(doc.Find("[data-test-target=restaurant-detail-price-category]").Text())))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+var webCacheBucket = []byte("pages")
+
+// webCache is a persistent, on-disk cache of downloaded pages keyed by the
+// sha256 of their URL, so re-running the ingest over the same url list does
+// not re-fetch pages that were already scraped.
+type webCache struct {
+	db *bbolt.DB
+}
+
+func openWebCache(path string) (*webCache, error) {
+	db, err := bbolt.Open(path, 0o644, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("openWebCache: %w", err)
+	}
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(webCacheBucket)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("openWebCache: %w", err)
+	}
+	return &webCache{db: db}, nil
+}
+
+func (c *webCache) Close() error {
+	return c.db.Close()
+}
+
+func webCacheKey(url string) []byte {
+	sum := sha256.Sum256([]byte(url))
+	return []byte(hex.EncodeToString(sum[:]))
+}
+
+// Get fetches the page body at url, transparently serving it from the cache
+// on every call after the first.
+func (c *webCache) Get(url string) ([]byte, error) {
+	key := webCacheKey(url)
+
+	var body []byte
+	if err := c.db.View(func(tx *bbolt.Tx) error {
+		if cached := tx.Bucket(webCacheBucket).Get(key); cached != nil {
+			body = append([]byte(nil), cached...)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("webCache.Get(%q): %w", url, err)
+	}
+	if body != nil {
+		return body, nil
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("webCache.Get(%q): %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("webCache.Get(%q): unexpected status %s", url, resp.Status)
+	}
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("webCache.Get(%q): %w", url, err)
+	}
+
+	if err := c.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(webCacheBucket).Put(key, body)
+	}); err != nil {
+		return nil, fmt.Errorf("webCache.Get(%q): %w", url, err)
+	}
+
+	return body, nil
+}
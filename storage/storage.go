@@ -0,0 +1,563 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// language=PostgreSQL
+	initSQL = `
+-- create extension if not exists postgis; ?
+
+create table if not exists "user"(
+	id int primary key not null,
+	mail text,
+	phone_number text
+);
+
+create table if not exists partner(
+	id int primary key not null,
+	headline text not null,
+	description text not null,
+	location point not null,
+	price_level smallint check ( price_level between 1 and 5)
+	-- + headline_banner
+);
+
+create table if not exists category(
+	id int primary key not null,
+	parent_id int null references category(id),
+	name text not null
+	-- + headline_banner
+);
+
+insert into category(id, parent_id, name) values
+	( 0, null, 'Root'),
+	( 1, 0, 'Eating out'),
+	( 2, 0, 'Supermarkets'),
+	( 3, 0, 'Clothes & etc.'),
+	( 4, 0, 'Entertainment'),
+	( 5, 0, 'Transport'),
+	( 6, 0, 'Health & Beauty'),
+	( 7, 1, 'Bars'),
+	( 8, 1, 'Restaurants'),
+	( 9, 1, 'Cafe'),
+	(10, 1, 'Burgers'),
+	(11, 1, 'Gyros')
+ON CONFLICT DO NOTHING;
+
+create table if not exists promotion(
+	id int primary key not null,
+	partner_id int not null references partner(id),
+	category_id int not null references category(id),
+	title text not null,
+	description text not null
+	-- + headline_banner
+);
+
+create table if not exists action(
+	id int primary key not null,
+	"type" text not null check (type in ('taken', 'expended')),
+	user_id int not null references "user"(id),
+	promotion_id int not null references promotion(id)
+);
+
+create table if not exists headline_banner(
+	url text primary key,
+	partner_id int null references partner(id),
+	promotion_id int null references promotion(id),
+	category_id int null references category(id),
+	image bytea not null
+);
+
+-- analytics on action need to bucket by day
+alter table action add column if not exists created_at timestamptz not null default now();
+
+-- action.id had no default; give it one so RecordAction doesn't have to assign ids itself
+create sequence if not exists action_id_seq owned by action.id;
+alter table action alter column id set default nextval('action_id_seq');
+select setval('action_id_seq', coalesce((select max(id) from action), 0) + 1, false);`
+
+	// language=PostgreSQL
+	categoriesSQL = `
+select category.id, category.name, headline_banner.url
+from category
+left join headline_banner on category.id = headline_banner.category_id
+where category.parent_id = ?::int;`
+
+	// language=PostgreSQL
+	categoriesNestedSQL = `
+with recursive cat as (
+	select category.id, category.parent_id, category.name, headline_banner.url, 1 as depth
+	from category
+	left join headline_banner on category.id = headline_banner.category_id
+	where category.parent_id = ?::int
+
+	union all
+
+	select category.id, category.parent_id, category.name, headline_banner.url, cat.depth + 1
+	from category
+	join cat on category.parent_id = cat.id
+	left join headline_banner on category.id = headline_banner.category_id
+	where cat.depth < ?::int
+)
+select id, parent_id, name, url from cat order by depth;`
+
+	// language=PostgreSQL
+	partnersSQL = `
+select
+    partner.id, partner.headline, partner.description,
+    partner.location[0] as latitude, partner.location[1] as longitude,
+    partner.price_level, headline_banner.url
+from partner
+left join public.headline_banner on partner.id = headline_banner.partner_id;`
+
+	// language=PostgreSQL
+	partnersGeoJSONSQL = `
+select row_to_json(fc)
+from (
+	select 'FeatureCollection'::text as type,
+	       coalesce(array_agg(row_to_json(f)), array[]::json[]) as features
+	from (
+		select
+			'Feature'::text as type,
+			ST_AsGeoJSON(ST_SetSRID(ST_MakePoint(partner.location[1], partner.location[0]), 4326))::json as geometry,
+			json_build_object(
+				'id', partner.id,
+				'headline', partner.headline,
+				'description', partner.description,
+				'price_level', partner.price_level,
+				'headline_banner_url', headline_banner.url
+			) as properties
+		from partner
+		left join headline_banner on partner.id = headline_banner.partner_id
+	) f
+) fc;`
+
+	// language=PostgreSQL
+	promotionByPartnerGeoJSONSQL = `
+select row_to_json(fc)
+from (
+	select 'FeatureCollection'::text as type,
+	       coalesce(array_agg(row_to_json(f)), array[]::json[]) as features
+	from (
+		select
+			'Feature'::text as type,
+			ST_AsGeoJSON(ST_SetSRID(ST_MakePoint(partner.location[1], partner.location[0]), 4326))::json as geometry,
+			json_build_object(
+				'id', promotion.id,
+				'headline', promotion.title,
+				'description', promotion.description,
+				'headline_banner_url', headline_banner.url
+			) as properties
+		from promotion
+		left join partner on promotion.partner_id = partner.id
+		left join headline_banner on promotion.id = headline_banner.promotion_id
+		where
+		    ?::int = 0 OR
+		    promotion.partner_id = ?::int
+	) f
+) fc;`
+
+	// language=PostgreSQL
+	bannerImagesSQL = `
+select image
+from headline_banner
+where url = $1;`
+
+	// language=PostgreSQL
+	promotionByPartnerSQL = `
+select
+    promotion.id,
+	promotion.title,
+	promotion.description,
+	headline_banner.url
+from promotion
+left join headline_banner on promotion.id = headline_banner.promotion_id
+where
+    ?::int = 0 OR
+    promotion.partner_id = ?::int;`
+
+	// language=PostgreSQL
+	promotionByGeoSQL = `
+select
+    promotion.id,
+	promotion.title,
+	promotion.description,
+	headline_banner.url,
+	ST_Distance(
+		ST_SetSRID(ST_MakePoint(partner.location[1], partner.location[0]), 4326)::geography,
+		ST_SetSRID(ST_MakePoint(?::float, ?::float), 4326)::geography
+	) as distance_m
+from promotion
+left join partner on promotion.partner_id = partner.id
+left join headline_banner on promotion.id = headline_banner.promotion_id
+where
+    ?::float = 0 OR ST_DWithin(
+		ST_SetSRID(ST_MakePoint(partner.location[1], partner.location[0]), 4326)::geography,
+		ST_SetSRID(ST_MakePoint(?::float, ?::float), 4326)::geography,
+		?::float
+	)
+order by distance_m asc
+limit ?::int offset ?::int;`
+
+	// language=PostgreSQL
+	promotionByGeoGeoJSONSQL = `
+select row_to_json(fc)
+from (
+	select 'FeatureCollection'::text as type,
+	       coalesce(array_agg(row_to_json(f)), array[]::json[]) as features
+	from (
+		select
+			'Feature'::text as type,
+			ST_AsGeoJSON(t.point)::json as geometry,
+			json_build_object(
+				'id', t.id,
+				'headline', t.title,
+				'description', t.description,
+				'headline_banner_url', t.url,
+				'distance_m', t.distance_m
+			) as properties
+		from (
+			select
+				promotion.id,
+				promotion.title,
+				promotion.description,
+				headline_banner.url,
+				ST_SetSRID(ST_MakePoint(partner.location[1], partner.location[0]), 4326) as point,
+				ST_Distance(
+					ST_SetSRID(ST_MakePoint(partner.location[1], partner.location[0]), 4326)::geography,
+					ST_SetSRID(ST_MakePoint(?::float, ?::float), 4326)::geography
+				) as distance_m
+			from promotion
+			left join partner on promotion.partner_id = partner.id
+			left join headline_banner on promotion.id = headline_banner.promotion_id
+			where
+			    ?::float = 0 OR ST_DWithin(
+					ST_SetSRID(ST_MakePoint(partner.location[1], partner.location[0]), 4326)::geography,
+					ST_SetSRID(ST_MakePoint(?::float, ?::float), 4326)::geography,
+					?::float
+				)
+			order by distance_m asc
+			limit ?::int offset ?::int
+		) t
+	) f
+) fc;`
+
+	// language=PostgreSQL
+	upsertPartnerSQL = `
+insert into partner(id, headline, description, location, price_level)
+values (?, ?, ?, point(?, ?), ?)
+on conflict (id) do update set
+	headline = excluded.headline,
+	description = excluded.description,
+	location = excluded.location,
+	price_level = excluded.price_level;`
+
+	// language=PostgreSQL
+	upsertPromotionSQL = `
+insert into promotion(id, partner_id, category_id, title, description)
+values (?, ?, ?, ?, ?)
+on conflict (id) do update set
+	partner_id = excluded.partner_id,
+	category_id = excluded.category_id,
+	title = excluded.title,
+	description = excluded.description;`
+
+	// language=PostgreSQL
+	upsertBannerSQL = `
+insert into headline_banner(url, partner_id, promotion_id, category_id, image)
+values (?, ?, ?, ?, ?)
+on conflict (url) do update set
+	partner_id = excluded.partner_id,
+	promotion_id = excluded.promotion_id,
+	category_id = excluded.category_id,
+	image = excluded.image;`
+
+	// language=PostgreSQL
+	recordActionSQL = `
+insert into action("type", user_id, promotion_id)
+values (?, ?, ?)
+returning id, "type", user_id, promotion_id, created_at;`
+
+	// language=PostgreSQL
+	userPromotionsSQL = `
+select promotion.id, promotion.title, promotion.description, headline_banner.url,
+       action.type as state, action.created_at
+from action
+join promotion on action.promotion_id = promotion.id
+left join headline_banner on promotion.id = headline_banner.promotion_id
+where
+    action.user_id = ?::int and
+    (?::text = '' or action.type = ?::text)
+order by action.created_at desc;`
+
+	// language=PostgreSQL
+	partnerStatsSQL = `
+select
+    promotion.id as promotion_id,
+    count(*) filter (where action.type = 'taken') as taken,
+    count(*) filter (where action.type = 'expended') as expended
+from promotion
+left join action on action.promotion_id = promotion.id
+where promotion.partner_id = ?::int
+group by promotion.id
+order by promotion.id;`
+)
+
+type Storage struct {
+	db *gorm.DB
+}
+
+func NewStorage(db *gorm.DB) (*Storage, error) {
+	if err := db.Exec(initSQL).Error; err != nil {
+		return nil, fmt.Errorf("NewStorage: %w", err)
+	}
+	return &Storage{db: db}, nil
+}
+
+// DB exposes the underlying *sql.DB for health checks and callers that need
+// to drop down below gorm.
+func (s *Storage) DB() (*sql.DB, error) {
+	return s.db.DB()
+}
+
+type Category struct {
+	Id       int    `json:"id" gorm:"id"`
+	ParentId int    `json:"-" gorm:"parent_id"`
+	Name     string `json:"name" gorm:"name"`
+	URL      string `json:"url" gorm:"url"`
+}
+
+func (s *Storage) GetCategories(parentId int) (*[]Category, error) {
+	var categories []Category
+	if err := s.db.Raw(categoriesSQL, parentId).Scan(&categories).Error; err != nil {
+		return nil, fmt.Errorf("GetCategories: %w", err)
+	}
+	return &categories, nil
+}
+
+// CategoryNested is a Category together with its own subtree, as returned
+// by GetCategoriesNested.
+type CategoryNested struct {
+	Category
+	Children []CategoryNested `json:"children,omitempty"`
+}
+
+// GetCategoriesNested loads the full subtree rooted at parentId, down to
+// depth levels, in a single recursive query, then assembles it into a tree
+// by grouping the flat row set by parent_id.
+func (s *Storage) GetCategoriesNested(parentId, depth int) ([]CategoryNested, error) {
+	var rows []Category
+	if err := s.db.Raw(categoriesNestedSQL, parentId, depth).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("GetCategoriesNested: %w", err)
+	}
+	return categoryChildren(rows, parentId), nil
+}
+
+// categoryChildren walks the flat row set and returns the direct children of
+// parentId, each with its own children attached recursively.
+func categoryChildren(rows []Category, parentId int) []CategoryNested {
+	var children []CategoryNested
+	for _, row := range rows {
+		if row.ParentId != parentId {
+			continue
+		}
+		children = append(children, CategoryNested{
+			Category: row,
+			Children: categoryChildren(rows, row.Id),
+		})
+	}
+	return children
+}
+
+type Partner struct {
+	Id          int     `json:"id" gorm:"id"`
+	Headline    string  `json:"headline" gorm:"headline"`
+	Description string  `json:"description" gorm:"description"`
+	Latitude    float64 `json:"latitude" gorm:"latitude"`
+	Longitude   float64 `json:"longitude" gorm:"longitude"`
+	PriceLevel  int8    `json:"price_level" gorm:"price_level"`
+	BannerURL   string  `json:"headline_banner_url" gorm:"url"`
+}
+
+func (s *Storage) GetPartners() ([]Partner, error) {
+	var partners []Partner
+	if err := s.db.Raw(partnersSQL).Scan(&partners).Error; err != nil {
+		return nil, fmt.Errorf("GetPartners: %w", err)
+	}
+	return partners, nil
+}
+
+// GetPartnersGeoJSON returns the same partners as GetPartners, already
+// assembled by PostGIS into an RFC 7946 FeatureCollection document.
+func (s *Storage) GetPartnersGeoJSON() ([]byte, error) {
+	var geojson []byte
+	if err := s.db.Raw(partnersGeoJSONSQL).Row().Scan(&geojson); err != nil {
+		return nil, fmt.Errorf("GetPartnersGeoJSON: %w", err)
+	}
+	return geojson, nil
+}
+
+// UpsertPartner inserts a partner or updates it in place when the id already exists,
+// so the ingest subsystem can be re-run against the same source URLs idempotently.
+func (s *Storage) UpsertPartner(p Partner) error {
+	if err := s.db.Exec(upsertPartnerSQL, p.Id, p.Headline, p.Description, p.Latitude, p.Longitude, p.PriceLevel).Error; err != nil {
+		return fmt.Errorf("UpsertPartner: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) GetBannerImageByURL(url string) ([]byte, error) {
+	conn, err := s.db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	var image []byte
+	if err = conn.QueryRow(bannerImagesSQL, url).Scan(&image); err != nil {
+		return nil, fmt.Errorf("GetBannerURLs: %w", err)
+	}
+
+	return image, nil
+}
+
+type Promotion struct {
+	Id             int     `json:"id" gorm:"id"`
+	Title          string  `json:"title" gorm:"title"`
+	Description    string  `json:"description" gorm:"description"`
+	Url            string  `json:"headline_banner_url" gorm:"url"`
+	DistanceMeters float64 `json:"distance_m,omitempty" gorm:"distance_m"`
+
+	PartnerId  int `json:"-" gorm:"-"`
+	CategoryId int `json:"-" gorm:"-"`
+}
+
+func (s *Storage) GetPromotionsByPartner(partner int) ([]Promotion, error) {
+	var promotions []Promotion
+	if err := s.db.Raw(promotionByPartnerSQL, partner, partner).Scan(&promotions).Error; err != nil {
+		return nil, fmt.Errorf("GetPromotionsByPartner: %w", err)
+	}
+	return promotions, nil
+}
+
+// GetPromotionsByPartnerGeoJSON returns the same promotions as
+// GetPromotionsByPartner, already assembled by PostGIS into an RFC 7946
+// FeatureCollection document whose geometry is each promotion's partner
+// location.
+func (s *Storage) GetPromotionsByPartnerGeoJSON(partner int) ([]byte, error) {
+	var geojson []byte
+	if err := s.db.Raw(promotionByPartnerGeoJSONSQL, partner, partner).Row().Scan(&geojson); err != nil {
+		return nil, fmt.Errorf("GetPromotionsByPartnerGeoJSON: %w", err)
+	}
+	return geojson, nil
+}
+
+// GetPromotionsByGeo returns promotions ordered by distance (closest first)
+// from (lat, long). When radius is non-zero, only promotions whose partner
+// is within radius meters are returned.
+func (s *Storage) GetPromotionsByGeo(lat, long, radius float64, limit, offset int) ([]Promotion, error) {
+	var promotions []Promotion
+	if err := s.db.Raw(promotionByGeoSQL, long, lat, radius, long, lat, radius, limit, offset).Scan(&promotions).Error; err != nil {
+		return nil, fmt.Errorf("GetPromotionsByGeo: %w", err)
+	}
+	return promotions, nil
+}
+
+// GetPromotionsByGeoGeoJSON returns the same promotions as
+// GetPromotionsByGeo, already assembled by PostGIS into an RFC 7946
+// FeatureCollection document carrying each promotion's distance_m.
+func (s *Storage) GetPromotionsByGeoGeoJSON(lat, long, radius float64, limit, offset int) ([]byte, error) {
+	var geojson []byte
+	if err := s.db.Raw(promotionByGeoGeoJSONSQL, long, lat, radius, long, lat, radius, limit, offset).Row().Scan(&geojson); err != nil {
+		return nil, fmt.Errorf("GetPromotionsByGeoGeoJSON: %w", err)
+	}
+	return geojson, nil
+}
+
+// UpsertPromotion inserts a promotion or updates it in place when the id already exists.
+func (s *Storage) UpsertPromotion(p Promotion) error {
+	if err := s.db.Exec(upsertPromotionSQL, p.Id, p.PartnerId, p.CategoryId, p.Title, p.Description).Error; err != nil {
+		return fmt.Errorf("UpsertPromotion: %w", err)
+	}
+	return nil
+}
+
+// Banner is a headline_banner row: an image attached to exactly one of a partner,
+// a promotion or a category.
+type Banner struct {
+	URL         string `gorm:"url"`
+	PartnerId   *int   `gorm:"partner_id"`
+	PromotionId *int   `gorm:"promotion_id"`
+	CategoryId  *int   `gorm:"category_id"`
+	Image       []byte `gorm:"image"`
+}
+
+// UpsertBanner inserts a banner image or updates it in place when the url already exists.
+func (s *Storage) UpsertBanner(b Banner) error {
+	if err := s.db.Exec(upsertBannerSQL, b.URL, b.PartnerId, b.PromotionId, b.CategoryId, b.Image).Error; err != nil {
+		return fmt.Errorf("UpsertBanner: %w", err)
+	}
+	return nil
+}
+
+// Action is a user taking or redeeming a promotion, as recorded by RecordAction.
+type Action struct {
+	Id          int       `json:"id" gorm:"id"`
+	Type        string    `json:"type" gorm:"type"`
+	UserId      int       `json:"user_id" gorm:"user_id"`
+	PromotionId int       `json:"promotion_id" gorm:"promotion_id"`
+	CreatedAt   time.Time `json:"created_at" gorm:"created_at"`
+}
+
+// RecordAction inserts a new action row. actionType must already have been
+// validated against the ('taken', 'expended') check constraint by the caller.
+func (s *Storage) RecordAction(userId, promotionId int, actionType string) (Action, error) {
+	var action Action
+	if err := s.db.Raw(recordActionSQL, actionType, userId, promotionId).Scan(&action).Error; err != nil {
+		return Action{}, fmt.Errorf("RecordAction: %w", err)
+	}
+	return action, nil
+}
+
+// UserPromotion is a promotion a user has taken or expended, for rendering
+// their coupon wallet.
+type UserPromotion struct {
+	Id          int       `json:"id" gorm:"id"`
+	Title       string    `json:"title" gorm:"title"`
+	Description string    `json:"description" gorm:"description"`
+	BannerURL   string    `json:"headline_banner_url" gorm:"url"`
+	State       string    `json:"state" gorm:"state"`
+	CreatedAt   time.Time `json:"created_at" gorm:"created_at"`
+}
+
+// GetUserPromotions returns the promotions a user has acted on, newest
+// first, optionally filtered to a single state ("taken" or "expended").
+func (s *Storage) GetUserPromotions(userId int, state string) ([]UserPromotion, error) {
+	var promotions []UserPromotion
+	if err := s.db.Raw(userPromotionsSQL, userId, state, state).Scan(&promotions).Error; err != nil {
+		return nil, fmt.Errorf("GetUserPromotions: %w", err)
+	}
+	return promotions, nil
+}
+
+// PromotionStats is the taken/expended action counts for a single promotion,
+// for partner-facing dashboards.
+type PromotionStats struct {
+	PromotionId int `json:"promotion_id" gorm:"promotion_id"`
+	Taken       int `json:"taken" gorm:"taken"`
+	Expended    int `json:"expended" gorm:"expended"`
+}
+
+// GetPartnerStats returns per-promotion taken/expended counts for a partner.
+func (s *Storage) GetPartnerStats(partnerId int) ([]PromotionStats, error) {
+	var stats []PromotionStats
+	if err := s.db.Raw(partnerStatsSQL, partnerId).Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("GetPartnerStats: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"os"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// testStorage opens a Storage against the POSTGRESQL env var used by main
+// and ingest, skipping the test when it isn't set so `go test ./...` stays
+// usable without a local PostGIS instance.
+func testStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	dsn := os.Getenv("POSTGRESQL")
+	if dsn == "" {
+		t.Skip("POSTGRESQL is not set, skipping test that needs a live PostGIS database")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm.Open: %s", err.Error())
+	}
+
+	s, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage: %s", err.Error())
+	}
+
+	return s
+}
+
+// TestGetPromotionsByGeo seeds two partners at known coordinates around
+// Red Square and checks that results come back nearest-first, and that
+// radius excludes the far partner.
+func TestGetPromotionsByGeo(t *testing.T) {
+	s := testStorage(t)
+
+	const (
+		redSquareLat, redSquareLong = 55.7539, 37.6208
+		nearPartnerId, nearPromoId  = 90001, 90011 // ~100m from Red Square
+		farPartnerId, farPromoId    = 90002, 90012 // ~20km from Red Square
+	)
+
+	fixtures := [][]any{
+		{90001, "Near Cafe", "GUM, Red Square, 3", 55.7546, 37.6215, 2},
+		{90002, "Far Cafe", "Skolkovo Innovation Center", 55.6995, 37.3560, 2},
+	}
+	for _, f := range fixtures {
+		if err := s.db.Exec(
+			`insert into partner(id, headline, description, location, price_level) values (?, ?, ?, point(?, ?), ?)
+			 on conflict (id) do update set location = excluded.location`,
+			f...,
+		).Error; err != nil {
+			t.Fatalf("seeding partner fixture: %s", err.Error())
+		}
+	}
+	if err := s.db.Exec(
+		`insert into promotion(id, partner_id, category_id, title, description) values
+			(?, ?, 0, 'Near promo', ''), (?, ?, 0, 'Far promo', '')
+		 on conflict (id) do nothing`,
+		nearPromoId, nearPartnerId, farPromoId, farPartnerId,
+	).Error; err != nil {
+		t.Fatalf("seeding promotion fixtures: %s", err.Error())
+	}
+	t.Cleanup(func() {
+		s.db.Exec(`delete from promotion where id in (?, ?)`, nearPromoId, farPromoId)
+		s.db.Exec(`delete from partner where id in (?, ?)`, nearPartnerId, farPartnerId)
+	})
+
+	tests := []struct {
+		name    string
+		radius  float64
+		wantIds []int
+	}{
+		{name: "no radius returns both, nearest first", radius: 0, wantIds: []int{nearPromoId, farPromoId}},
+		{name: "radius excludes the far partner", radius: 1000, wantIds: []int{nearPromoId}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetPromotionsByGeo(redSquareLat, redSquareLong, tt.radius, 10, 0)
+			if err != nil {
+				t.Fatalf("GetPromotionsByGeo: %s", err.Error())
+			}
+
+			var gotIds []int
+			for _, p := range got {
+				if p.Id == nearPromoId || p.Id == farPromoId {
+					gotIds = append(gotIds, p.Id)
+				}
+			}
+
+			if len(gotIds) != len(tt.wantIds) {
+				t.Fatalf("got ids %v, want %v", gotIds, tt.wantIds)
+			}
+			for i, id := range tt.wantIds {
+				if gotIds[i] != id {
+					t.Errorf("got ids %v, want %v", gotIds, tt.wantIds)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Package web provides consistent JSON error responses and a per-request
+// correlation id, so handlers never leak raw driver errors to clients.
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+// WithRequestID generates a request id, echoes it as X-Request-ID, and makes
+// it available to the handler (and to BadRequest/NotFound/... below) via the
+// request context.
+func WithRequestID(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := newRequestID()
+		w.Header().Set("X-Request-ID", id)
+		next(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	}
+}
+
+// RequestID returns the id WithRequestID attached to r, or "" if none was attached.
+func RequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey).(string)
+	return id
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
+
+type errorBody struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id"`
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string, logErr error) {
+	if logErr != nil {
+		log.Printf("request_id=%s status=%d: %s", RequestID(r), status, logErr.Error())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(errorBody{Error: message, RequestID: RequestID(r)})
+}
+
+// BadRequest reports a malformed or missing request parameter.
+func BadRequest(w http.ResponseWriter, r *http.Request, message string) {
+	writeError(w, r, http.StatusBadRequest, message, nil)
+}
+
+// NotFound reports that the requested resource doesn't exist.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusNotFound, "not found", nil)
+}
+
+// ServiceUnavailable reports that a dependency (typically the database) is
+// unreachable. err is logged server-side but never sent to the client.
+func ServiceUnavailable(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, http.StatusServiceUnavailable, "service unavailable", err)
+}
+
+// InternalServerError reports an unclassified failure. err is logged
+// server-side but never sent to the client.
+func InternalServerError(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, http.StatusInternalServerError, "internal server error", err)
+}
+
+// OK writes body as the JSON response.
+func OK(w http.ResponseWriter, r *http.Request, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(body)
+}